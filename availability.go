@@ -0,0 +1,461 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// AvailabilityRule is a recurring window during which appointments can be
+// booked, e.g. "every Tuesday from 09:00 to 17:00 in 30 minute slots".
+type AvailabilityRule struct {
+	ID          int64   `json:"id"`
+	Weekday     int     `json:"weekday"` // 0 (Sunday) through 6 (Saturday), per time.Weekday
+	StartTime   string  `json:"startTime"`
+	EndTime     string  `json:"endTime"`
+	SlotMinutes int     `json:"slotMinutes"`
+	ValidFrom   *string `json:"validFrom,omitempty"`
+	ValidTo     *string `json:"validTo,omitempty"`
+}
+
+// BlackoutPeriod blocks a window of time from being booked, optionally
+// recurring via an RFC 5545 RRULE (e.g. a weekly lunch break or a public
+// holiday list).
+type BlackoutPeriod struct {
+	ID      int64     `json:"id"`
+	StartTS time.Time `json:"startTs"`
+	EndTS   time.Time `json:"endTs"`
+	Reason  string    `json:"reason"`
+	RRule   *string   `json:"rrule,omitempty"`
+}
+
+// requireBearerToken guards admin endpoints with a static bearer token read
+// from the ADMIN_API_TOKEN environment variable.
+func requireBearerToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		expected := os.Getenv("ADMIN_API_TOKEN")
+		if expected == "" {
+			zerolog.Ctx(r.Context()).Error().Msg("ADMIN_API_TOKEN environment variable not set")
+			handleError(w, r, "Server is not configured for admin access", http.StatusInternalServerError)
+			return
+		}
+
+		authHeader := r.Header.Get("Authorization")
+		if authHeader != "Bearer "+expected {
+			handleError(w, r, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (a *App) handleAvailabilityRules(w http.ResponseWriter, r *http.Request) {
+	logger := zerolog.Ctx(r.Context())
+	w.Header().Set("Content-Type", "application/json")
+	db := a.db
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := db.Query(`SELECT id, weekday, start_time, end_time, slot_minutes, valid_from, valid_to FROM availability_rules ORDER BY weekday, start_time`)
+		if err != nil {
+			handleError(w, r, "Database error", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		var rules []AvailabilityRule
+		for rows.Next() {
+			var rule AvailabilityRule
+			var validFrom, validTo sql.NullString
+			if err := rows.Scan(&rule.ID, &rule.Weekday, &rule.StartTime, &rule.EndTime, &rule.SlotMinutes, &validFrom, &validTo); err != nil {
+				handleError(w, r, "Database error", http.StatusInternalServerError)
+				return
+			}
+			if validFrom.Valid {
+				rule.ValidFrom = &validFrom.String
+			}
+			if validTo.Valid {
+				rule.ValidTo = &validTo.String
+			}
+			rules = append(rules, rule)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"rules": rules})
+
+	case http.MethodPost:
+		var rule AvailabilityRule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			handleError(w, r, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if rule.Weekday < 0 || rule.Weekday > 6 || rule.StartTime == "" || rule.EndTime == "" || rule.SlotMinutes <= 0 {
+			handleError(w, r, "weekday, startTime, endTime, and a positive slotMinutes are required", http.StatusBadRequest)
+			return
+		}
+		startMinutes, startErr := minutesOfDay(rule.StartTime)
+		endMinutes, endErr := minutesOfDay(rule.EndTime)
+		if startErr != nil || endErr != nil {
+			handleError(w, r, "startTime and endTime must be in HH:MM format", http.StatusBadRequest)
+			return
+		}
+		if endMinutes <= startMinutes {
+			handleError(w, r, "endTime must be after startTime; overnight rules are not supported", http.StatusBadRequest)
+			return
+		}
+
+		err := db.QueryRow(`
+			INSERT INTO availability_rules (weekday, start_time, end_time, slot_minutes, valid_from, valid_to)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id`,
+			rule.Weekday, rule.StartTime, rule.EndTime, rule.SlotMinutes, rule.ValidFrom, rule.ValidTo,
+		).Scan(&rule.ID)
+		if err != nil {
+			logger.Error().Err(err).Msg("Error creating availability rule")
+			handleError(w, r, "Error creating availability rule", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "rule": rule})
+
+	case http.MethodDelete:
+		id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+		if err != nil {
+			handleError(w, r, "A valid id query parameter is required", http.StatusBadRequest)
+			return
+		}
+		if _, err := db.Exec(`DELETE FROM availability_rules WHERE id = $1`, id); err != nil {
+			logger.Error().Err(err).Int64("id", id).Msg("Error deleting availability rule")
+			handleError(w, r, "Error deleting availability rule", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+
+	default:
+		handleError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (a *App) handleBlackoutPeriods(w http.ResponseWriter, r *http.Request) {
+	logger := zerolog.Ctx(r.Context())
+	w.Header().Set("Content-Type", "application/json")
+	db := a.db
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := db.Query(`SELECT id, start_ts, end_ts, reason, rrule FROM blackout_periods ORDER BY start_ts`)
+		if err != nil {
+			handleError(w, r, "Database error", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		var periods []BlackoutPeriod
+		for rows.Next() {
+			var period BlackoutPeriod
+			var rrule sql.NullString
+			if err := rows.Scan(&period.ID, &period.StartTS, &period.EndTS, &period.Reason, &rrule); err != nil {
+				handleError(w, r, "Database error", http.StatusInternalServerError)
+				return
+			}
+			if rrule.Valid {
+				period.RRule = &rrule.String
+			}
+			periods = append(periods, period)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"blackouts": periods})
+
+	case http.MethodPost:
+		var period BlackoutPeriod
+		if err := json.NewDecoder(r.Body).Decode(&period); err != nil {
+			handleError(w, r, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if period.StartTS.IsZero() || period.EndTS.IsZero() || !period.EndTS.After(period.StartTS) {
+			handleError(w, r, "startTs and endTs are required, with endTs after startTs", http.StatusBadRequest)
+			return
+		}
+
+		err := db.QueryRow(`
+			INSERT INTO blackout_periods (start_ts, end_ts, reason, rrule)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id`,
+			period.StartTS, period.EndTS, period.Reason, period.RRule,
+		).Scan(&period.ID)
+		if err != nil {
+			logger.Error().Err(err).Msg("Error creating blackout period")
+			handleError(w, r, "Error creating blackout period", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "blackout": period})
+
+	case http.MethodDelete:
+		id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+		if err != nil {
+			handleError(w, r, "A valid id query parameter is required", http.StatusBadRequest)
+			return
+		}
+		if _, err := db.Exec(`DELETE FROM blackout_periods WHERE id = $1`, id); err != nil {
+			logger.Error().Err(err).Int64("id", id).Msg("Error deleting blackout period")
+			handleError(w, r, "Error deleting blackout period", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+
+	default:
+		handleError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// timeInterval is a half-open [Start, End) range within a single day,
+// expressed as "HH:MM" strings so it composes directly with the slot times
+// generateTimeSlots produces.
+type timeInterval struct {
+	Start string
+	End   string
+}
+
+// rulesForDate returns the availability rules whose weekday matches date
+// and whose [valid_from, valid_to] window (if set) covers it.
+func rulesForDate(db *sql.DB, date time.Time) ([]AvailabilityRule, error) {
+	dateStr := date.Format("2006-01-02")
+	rows, err := db.Query(`
+		SELECT id, weekday, start_time, end_time, slot_minutes, valid_from, valid_to
+		FROM availability_rules
+		WHERE weekday = $1
+		  AND (valid_from IS NULL OR valid_from <= $2)
+		  AND (valid_to IS NULL OR valid_to >= $2)`,
+		int(date.Weekday()), dateStr)
+	if err != nil {
+		return nil, fmt.Errorf("querying availability rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []AvailabilityRule
+	for rows.Next() {
+		var rule AvailabilityRule
+		var validFrom, validTo sql.NullString
+		if err := rows.Scan(&rule.ID, &rule.Weekday, &rule.StartTime, &rule.EndTime, &rule.SlotMinutes, &validFrom, &validTo); err != nil {
+			return nil, fmt.Errorf("scanning availability rule: %w", err)
+		}
+		if validFrom.Valid {
+			rule.ValidFrom = &validFrom.String
+		}
+		if validTo.Valid {
+			rule.ValidTo = &validTo.String
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// blackoutIntervalsForDate loads every blackout period and, for recurring
+// ones, expands their RRULE on the fly to see whether they cover the
+// requested date. Non-matching or non-recurring-but-different-day periods
+// are skipped without being materialized into the database.
+func blackoutIntervalsForDate(db *sql.DB, date time.Time) ([]timeInterval, error) {
+	rows, err := db.Query(`SELECT start_ts, end_ts, rrule FROM blackout_periods`)
+	if err != nil {
+		return nil, fmt.Errorf("querying blackout periods: %w", err)
+	}
+	defer rows.Close()
+
+	var intervals []timeInterval
+	for rows.Next() {
+		var start, end time.Time
+		var rrule sql.NullString
+		if err := rows.Scan(&start, &end, &rrule); err != nil {
+			return nil, fmt.Errorf("scanning blackout period: %w", err)
+		}
+
+		var rule *string
+		if rrule.Valid {
+			rule = &rrule.String
+		}
+
+		if occursOnDate(start, rule, date) {
+			intervals = append(intervals, timeInterval{
+				Start: start.Format("15:04"),
+				End:   end.Format("15:04"),
+			})
+		}
+	}
+	return intervals, rows.Err()
+}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// occursOnDate reports whether a blackout period starting at start and
+// recurring per rrule has an occurrence on date. rrule nil means the
+// period is a one-off and only covers date if start falls on the same day.
+// Supports FREQ=WEEKLY;BYDAY=... and FREQ=DAILY;INTERVAL=n, the minimum
+// required for recurring holidays and lunch breaks.
+func occursOnDate(start time.Time, rrule *string, date time.Time) bool {
+	startDay := start.Truncate(24 * time.Hour)
+	day := date.Truncate(24 * time.Hour)
+
+	if rrule == nil || *rrule == "" {
+		return startDay.Equal(day)
+	}
+
+	if day.Before(startDay) {
+		return false
+	}
+
+	params := make(map[string]string)
+	for _, part := range strings.Split(*rrule, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) == 2 {
+			params[strings.ToUpper(kv[0])] = kv[1]
+		}
+	}
+
+	switch params["FREQ"] {
+	case "WEEKLY":
+		byDay := params["BYDAY"]
+		if byDay == "" {
+			return date.Weekday() == start.Weekday()
+		}
+		for _, d := range strings.Split(byDay, ",") {
+			if wd, ok := rruleWeekdays[strings.ToUpper(strings.TrimSpace(d))]; ok && wd == date.Weekday() {
+				return true
+			}
+		}
+		return false
+
+	case "DAILY":
+		interval := 1
+		if v, err := strconv.Atoi(params["INTERVAL"]); err == nil && v > 0 {
+			interval = v
+		}
+		daysSince := int(day.Sub(startDay).Hours() / 24)
+		return daysSince%interval == 0
+
+	default:
+		log.Warn().Str("rrule", *rrule).Msg("Unsupported RRULE FREQ, ignoring blackout period")
+		return false
+	}
+}
+
+// generateAvailableSlotsForDate computes the bookable "HH:MM" slots for
+// date by intersecting every matching availability rule with the day,
+// subtracting blackout intervals (including expanded recurring ones) and
+// any already-booked times.
+func generateAvailableSlotsForDate(db *sql.DB, date time.Time, bookedTimes map[string]bool) ([]string, error) {
+	rules, err := rulesForDate(db, date)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	blackouts, err := blackoutIntervalsForDate(db, date)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var slots []string
+	for _, rule := range rules {
+		for _, t := range slotTimesForRule(rule) {
+			if seen[t] {
+				continue
+			}
+			if inAnyInterval(t, blackouts) {
+				continue
+			}
+			if bookedTimes[t] {
+				continue
+			}
+			seen[t] = true
+			slots = append(slots, t)
+		}
+	}
+	return slots, nil
+}
+
+// isSlotAvailable reports whether hhmm on date falls within any matching
+// availability rule's slot grid and isn't covered by a blackout period.
+// It does not check existing bookings; callers check that separately.
+func isSlotAvailable(db *sql.DB, date time.Time, hhmm string) (bool, error) {
+	rules, err := rulesForDate(db, date)
+	if err != nil {
+		return false, err
+	}
+	if len(rules) == 0 {
+		return false, nil
+	}
+
+	blackouts, err := blackoutIntervalsForDate(db, date)
+	if err != nil {
+		return false, err
+	}
+	if inAnyInterval(hhmm, blackouts) {
+		return false, nil
+	}
+
+	for _, rule := range rules {
+		if hhmm < rule.StartTime || hhmm >= rule.EndTime {
+			continue
+		}
+		for _, t := range slotTimesForRule(rule) {
+			if t == hhmm {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// minutesOfDay parses "HH:MM" into minutes since midnight.
+func minutesOfDay(hhmm string) (int, error) {
+	parsed, err := time.Parse("15:04", hhmm)
+	if err != nil {
+		return 0, err
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// formatMinutesOfDay is the inverse of minutesOfDay.
+func formatMinutesOfDay(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// slotTimesForRule returns the "HH:MM" grid for rule, stepping by
+// SlotMinutes from StartTime up to (not including) EndTime. Minutes are
+// compared as plain integers rather than wrapping clock-of-day strings, so
+// a rule whose grid would otherwise run past midnight is simply truncated
+// at EndTime instead of looping forever.
+func slotTimesForRule(rule AvailabilityRule) []string {
+	start, err := minutesOfDay(rule.StartTime)
+	if err != nil {
+		return nil
+	}
+	end, err := minutesOfDay(rule.EndTime)
+	if err != nil {
+		return nil
+	}
+
+	var times []string
+	for t := start; t < end; t += rule.SlotMinutes {
+		times = append(times, formatMinutesOfDay(t))
+	}
+	return times
+}
+
+func inAnyInterval(hhmm string, intervals []timeInterval) bool {
+	for _, interval := range intervals {
+		if hhmm >= interval.Start && hhmm < interval.End {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func mustParseDate(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		t.Fatalf("parsing date %q: %v", value, err)
+	}
+	return parsed
+}
+
+func TestOccursOnDateOneOff(t *testing.T) {
+	start := mustParseDate(t, "2026-03-05")
+	if !occursOnDate(start, nil, mustParseDate(t, "2026-03-05")) {
+		t.Error("expected a one-off blackout to occur on its own start date")
+	}
+	if occursOnDate(start, nil, mustParseDate(t, "2026-03-06")) {
+		t.Error("expected a one-off blackout not to occur on a different date")
+	}
+}
+
+func TestOccursOnDateWeeklyByDay(t *testing.T) {
+	start := mustParseDate(t, "2026-03-03") // a Tuesday
+	rrule := "FREQ=WEEKLY;BYDAY=TU,TH"
+
+	cases := map[string]bool{
+		"2026-03-03": true,  // Tuesday, first occurrence
+		"2026-03-05": true,  // Thursday
+		"2026-03-04": false, // Wednesday
+		"2026-03-01": false, // before start
+	}
+	for date, want := range cases {
+		if got := occursOnDate(start, &rrule, mustParseDate(t, date)); got != want {
+			t.Errorf("occursOnDate(%s) = %v, want %v", date, got, want)
+		}
+	}
+}
+
+func TestOccursOnDateDailyInterval(t *testing.T) {
+	start := mustParseDate(t, "2026-03-01")
+	rrule := "FREQ=DAILY;INTERVAL=3"
+
+	cases := map[string]bool{
+		"2026-03-01": true,
+		"2026-03-02": false,
+		"2026-03-04": true,
+		"2026-03-07": true,
+		"2026-03-06": false,
+	}
+	for date, want := range cases {
+		if got := occursOnDate(start, &rrule, mustParseDate(t, date)); got != want {
+			t.Errorf("occursOnDate(%s) = %v, want %v", date, got, want)
+		}
+	}
+}
+
+func TestOccursOnDateUnsupportedFreq(t *testing.T) {
+	start := mustParseDate(t, "2026-03-01")
+	rrule := "FREQ=MONTHLY;BYMONTHDAY=1"
+	if occursOnDate(start, &rrule, mustParseDate(t, "2026-04-01")) {
+		t.Error("expected an unsupported FREQ to never match, not to error out")
+	}
+}
+
+func TestMinutesOfDay(t *testing.T) {
+	got, err := minutesOfDay("09:45")
+	if err != nil {
+		t.Fatalf("minutesOfDay returned error: %v", err)
+	}
+	if got != 9*60+45 {
+		t.Errorf("minutesOfDay(09:45) = %d, want %d", got, 9*60+45)
+	}
+	if _, err := minutesOfDay("not-a-time"); err == nil {
+		t.Error("expected an error for an unparseable time")
+	}
+}
+
+func TestSlotTimesForRuleStopsAtEndTimeWithoutWrapping(t *testing.T) {
+	// A naive string-wrapping implementation loops forever here: 23:00 + 90m
+	// wraps to 00:30, which still sorts before the "23:50" end string.
+	rule := AvailabilityRule{StartTime: "23:00", EndTime: "23:50", SlotMinutes: 90}
+
+	got := slotTimesForRule(rule)
+	want := []string{"23:00"}
+	if len(got) != len(want) || got[0] != want[0] {
+		t.Fatalf("slotTimesForRule(%+v) = %v, want %v", rule, got, want)
+	}
+}
+
+func TestSlotTimesForRule(t *testing.T) {
+	rule := AvailabilityRule{StartTime: "09:00", EndTime: "10:30", SlotMinutes: 30}
+	got := slotTimesForRule(rule)
+	want := []string{"09:00", "09:30", "10:00"}
+	if len(got) != len(want) {
+		t.Fatalf("slotTimesForRule(%+v) = %v, want %v", rule, got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("slotTimesForRule(%+v)[%d] = %q, want %q", rule, i, got[i], want[i])
+		}
+	}
+}
+
+func TestInAnyInterval(t *testing.T) {
+	intervals := []timeInterval{
+		{Start: "12:00", End: "13:00"},
+		{Start: "15:00", End: "15:30"},
+	}
+	if !inAnyInterval("12:30", intervals) {
+		t.Error("expected 12:30 to fall inside the first interval")
+	}
+	if inAnyInterval("13:00", intervals) {
+		t.Error("expected interval end to be exclusive")
+	}
+	if inAnyInterval("14:00", intervals) {
+		t.Error("expected 14:00 to fall outside both intervals")
+	}
+}
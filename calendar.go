@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// berlinVTimeZone is a static VTIMEZONE block for Europe/Berlin, valid for the
+// CEST/CET transitions used by this calendar. It is embedded in every
+// iCalendar document we generate, ahead of its VEVENT, so clients without their own tzdata can still
+// render DTSTART/DTEND correctly.
+const berlinVTimeZone = `BEGIN:VTIMEZONE
+TZID:Europe/Berlin
+BEGIN:DAYLIGHT
+TZOFFSETFROM:+0100
+TZOFFSETTO:+0200
+TZNAME:CEST
+DTSTART:19700329T020000
+RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU
+END:DAYLIGHT
+BEGIN:STANDARD
+TZOFFSETFROM:+0200
+TZOFFSETTO:+0100
+TZNAME:CET
+DTSTART:19701025T030000
+RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU
+END:STANDARD
+END:VTIMEZONE
+`
+
+const appointmentDuration = 30 * time.Minute
+
+// icsEscape escapes text per RFC 5545 section 3.3.11.
+func icsEscape(s string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		`;`, `\;`,
+		`,`, `\,`,
+		"\n", `\n`,
+	)
+	return replacer.Replace(s)
+}
+
+// buildAppointmentICS renders a single-VEVENT iCalendar document for an
+// appointment. method is the iTIP method, e.g. "REQUEST" for a new/updated
+// booking or "CANCEL" for a cancellation.
+func buildAppointmentICS(ctx context.Context, appointment Appointment, method string) (string, error) {
+	start, err := time.ParseInLocation("2006-01-02 15:04", appointment.Date+" "+appointment.Time, berlinLocation(ctx))
+	if err != nil {
+		return "", fmt.Errorf("parsing appointment start: %w", err)
+	}
+	end := start.Add(appointmentDuration)
+
+	organizerEmail := os.Getenv("CALENDAR_ORGANIZER_EMAIL")
+	if organizerEmail == "" {
+		organizerEmail = "info@af-automation-systems.com"
+	}
+
+	summary := fmt.Sprintf("Beratungstermin mit %s", appointment.Name)
+	var description string
+	if appointment.Message != nil {
+		description = *appointment.Message
+	}
+
+	status := "CONFIRMED"
+	sequence := 0
+	if method == "CANCEL" {
+		status = "CANCELLED"
+		sequence = 1
+	}
+
+	var b bytes.Buffer
+	b.WriteString("BEGIN:VCALENDAR\r\n")
+	b.WriteString("VERSION:2.0\r\n")
+	b.WriteString("PRODID:-//AF Automation Systems//Appointments//DE\r\n")
+	b.WriteString("CALSCALE:GREGORIAN\r\n")
+	b.WriteString(fmt.Sprintf("METHOD:%s\r\n", method))
+	b.WriteString(strings.ReplaceAll(berlinVTimeZone, "\n", "\r\n"))
+	b.WriteString("BEGIN:VEVENT\r\n")
+	b.WriteString(fmt.Sprintf("UID:%d@af-automation-systems.com\r\n", appointment.ID))
+	b.WriteString(fmt.Sprintf("DTSTAMP:%s\r\n", time.Now().UTC().Format("20060102T150405Z")))
+	b.WriteString(fmt.Sprintf("DTSTART;TZID=Europe/Berlin:%s\r\n", start.Format("20060102T150405")))
+	b.WriteString(fmt.Sprintf("DTEND;TZID=Europe/Berlin:%s\r\n", end.Format("20060102T150405")))
+	b.WriteString(fmt.Sprintf("SUMMARY:%s\r\n", icsEscape(summary)))
+	if description != "" {
+		b.WriteString(fmt.Sprintf("DESCRIPTION:%s\r\n", icsEscape(description)))
+	}
+	b.WriteString(fmt.Sprintf("ORGANIZER;CN=AF Automation Systems:mailto:%s\r\n", organizerEmail))
+	b.WriteString(fmt.Sprintf("ATTENDEE;CN=%s;RSVP=TRUE:mailto:%s\r\n", icsEscape(appointment.Name), appointment.Email))
+	b.WriteString(fmt.Sprintf("SEQUENCE:%d\r\n", sequence))
+	b.WriteString(fmt.Sprintf("STATUS:%s\r\n", status))
+	b.WriteString("END:VEVENT\r\n")
+	b.WriteString("END:VCALENDAR\r\n")
+
+	return b.String(), nil
+}
+
+func berlinLocation(ctx context.Context) *time.Location {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		zerolog.Ctx(ctx).Warn().Err(err).Msg("Failed to load Europe/Berlin location, falling back to UTC")
+		return time.UTC
+	}
+	return loc
+}
+
+// caldavConfig holds CalDAV connection settings. loadCaldavConfig reads them
+// from the environment; its ok result is false if CalDAV integration is not
+// configured, in which case callers should treat calendar sync as a no-op
+// rather than an error.
+type caldavConfig struct {
+	baseURL  string
+	user     string
+	password string
+}
+
+func loadCaldavConfig() (caldavConfig, bool) {
+	cfg := caldavConfig{
+		baseURL:  strings.TrimSuffix(os.Getenv("CALDAV_URL"), "/"),
+		user:     os.Getenv("CALDAV_USER"),
+		password: os.Getenv("CALDAV_PASSWORD"),
+	}
+	return cfg, cfg.baseURL != ""
+}
+
+// pushAppointmentToCaldav PUTs the given iCalendar document to the
+// configured calendar under <uid>.ics.
+func pushAppointmentToCaldav(ctx context.Context, uid int64, icsData string) error {
+	cfg, ok := loadCaldavConfig()
+	if !ok {
+		zerolog.Ctx(ctx).Debug().Msg("CalDAV not configured, skipping calendar push")
+		return nil
+	}
+
+	url := fmt.Sprintf("%s/%d.ics", cfg.baseURL, uid)
+	req, err := http.NewRequest(http.MethodPut, url, strings.NewReader(icsData))
+	if err != nil {
+		return fmt.Errorf("building CalDAV PUT request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/calendar; charset=utf-8")
+	req.SetBasicAuth(cfg.user, cfg.password)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("CalDAV PUT request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("CalDAV PUT returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	zerolog.Ctx(ctx).Info().Int64("uid", uid).Msg("Pushed appointment to CalDAV calendar")
+	return nil
+}
+
+// deleteAppointmentFromCaldav removes <uid>.ics from the configured
+// calendar.
+func deleteAppointmentFromCaldav(ctx context.Context, uid int64) error {
+	cfg, ok := loadCaldavConfig()
+	if !ok {
+		zerolog.Ctx(ctx).Debug().Msg("CalDAV not configured, skipping calendar delete")
+		return nil
+	}
+
+	url := fmt.Sprintf("%s/%d.ics", cfg.baseURL, uid)
+	req, err := http.NewRequest(http.MethodDelete, url, nil)
+	if err != nil {
+		return fmt.Errorf("building CalDAV DELETE request: %w", err)
+	}
+	req.SetBasicAuth(cfg.user, cfg.password)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("CalDAV DELETE request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("CalDAV DELETE returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	zerolog.Ctx(ctx).Info().Int64("uid", uid).Msg("Deleted appointment from CalDAV calendar")
+	return nil
+}
+
+// calendarQueryReportTemplate is a minimal CalDAV REPORT body requesting all
+// VEVENTs overlapping the given UTC time range, per RFC 4791 section 7.8.
+const calendarQueryReportTemplate = `<?xml version="1.0" encoding="utf-8" ?>
+<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop>
+    <D:getetag/>
+    <C:calendar-data/>
+  </D:prop>
+  <C:filter>
+    <C:comp-filter name="VCALENDAR">
+      <C:comp-filter name="VEVENT">
+        <C:time-range start="%s" end="%s"/>
+      </C:comp-filter>
+    </C:comp-filter>
+  </C:filter>
+</C:calendar-query>`
+
+// fetchCaldavBookedTimes runs a calendar-query REPORT for the given date
+// (Europe/Berlin, local day) and returns the set of "HH:MM" times already
+// occupied by VEVENTs on the CalDAV server, so slots blocked directly on the
+// owner's calendar show up as booked even if they didn't originate from
+// this API.
+func fetchCaldavBookedTimes(ctx context.Context, date string) (map[string]bool, error) {
+	booked := make(map[string]bool)
+
+	cfg, ok := loadCaldavConfig()
+	if !ok {
+		return booked, nil
+	}
+
+	dayStart, err := time.ParseInLocation("2006-01-02", date, berlinLocation(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("parsing date for CalDAV query: %w", err)
+	}
+	dayEnd := dayStart.Add(24 * time.Hour)
+
+	body := fmt.Sprintf(calendarQueryReportTemplate,
+		dayStart.UTC().Format("20060102T150405Z"),
+		dayEnd.UTC().Format("20060102T150405Z"))
+
+	req, err := http.NewRequest("REPORT", cfg.baseURL+"/", strings.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("building CalDAV REPORT request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	req.Header.Set("Depth", "1")
+	req.SetBasicAuth(cfg.user, cfg.password)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("CalDAV REPORT request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("CalDAV REPORT returned %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading CalDAV REPORT response: %w", err)
+	}
+
+	for _, start := range extractDTStarts(ctx, string(respBody)) {
+		local := start.In(berlinLocation(ctx))
+		if local.Format("2006-01-02") == date {
+			booked[local.Format("15:04")] = true
+		}
+	}
+
+	return booked, nil
+}
+
+// extractDTStarts pulls every DTSTART value out of a multistatus response
+// body containing embedded calendar-data. It is deliberately tolerant of
+// the exact XML/ICS structure since CalDAV servers vary in formatting.
+func extractDTStarts(ctx context.Context, multistatus string) []time.Time {
+	var starts []time.Time
+	for _, line := range strings.Split(multistatus, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "DTSTART") {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+		if t, err := time.ParseInLocation("20060102T150405", value, berlinLocation(ctx)); err == nil {
+			starts = append(starts, t)
+			continue
+		}
+		if t, err := time.Parse("20060102T150405Z", value); err == nil {
+			starts = append(starts, t)
+		}
+	}
+	return starts
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestIcsEscape(t *testing.T) {
+	cases := map[string]string{
+		`Hello, World; please\confirm`: `Hello\, World\; please\\confirm`,
+		"line one\nline two":           `line one\nline two`,
+		"no special chars":             "no special chars",
+	}
+	for input, want := range cases {
+		if got := icsEscape(input); got != want {
+			t.Errorf("icsEscape(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestBuildAppointmentICS(t *testing.T) {
+	appointment := Appointment{
+		ID:    42,
+		Name:  "Jane, Doe",
+		Email: "jane@example.com",
+		Date:  "2026-07-27",
+		Time:  "14:00",
+	}
+
+	ics, err := buildAppointmentICS(context.Background(), appointment, "REQUEST")
+	if err != nil {
+		t.Fatalf("buildAppointmentICS returned error: %v", err)
+	}
+
+	for _, want := range []string{
+		"METHOD:REQUEST\r\n",
+		"UID:42@af-automation-systems.com\r\n",
+		"DTSTART;TZID=Europe/Berlin:20260727T140000\r\n",
+		"DTEND;TZID=Europe/Berlin:20260727T143000\r\n",
+		"SUMMARY:Beratungstermin mit Jane\\, Doe\r\n",
+		"STATUS:CONFIRMED\r\n",
+		"SEQUENCE:0\r\n",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("expected generated ICS to contain %q, got:\n%s", want, ics)
+		}
+	}
+}
+
+func TestBuildAppointmentICSCancel(t *testing.T) {
+	appointment := Appointment{ID: 7, Name: "John Doe", Email: "john@example.com", Date: "2026-07-27", Time: "09:00"}
+
+	ics, err := buildAppointmentICS(context.Background(), appointment, "CANCEL")
+	if err != nil {
+		t.Fatalf("buildAppointmentICS returned error: %v", err)
+	}
+	if !strings.Contains(ics, "METHOD:CANCEL\r\n") || !strings.Contains(ics, "STATUS:CANCELLED\r\n") || !strings.Contains(ics, "SEQUENCE:1\r\n") {
+		t.Errorf("expected a cancellation ICS to bump sequence and mark CANCELLED, got:\n%s", ics)
+	}
+}
+
+func TestBuildAppointmentICSInvalidDate(t *testing.T) {
+	appointment := Appointment{ID: 1, Name: "Jane", Email: "jane@example.com", Date: "not-a-date", Time: "09:00"}
+	if _, err := buildAppointmentICS(context.Background(), appointment, "REQUEST"); err == nil {
+		t.Error("expected an error for an unparseable appointment date")
+	}
+}
+
+func TestExtractDTStarts(t *testing.T) {
+	multistatus := "DTSTAMP:20260101T000000Z\nDTSTART:20260727T120000\nOTHER:value\nDTSTART:20260728T090000Z\n"
+
+	starts := extractDTStarts(context.Background(), multistatus)
+	if len(starts) != 2 {
+		t.Fatalf("expected 2 DTSTART values, got %d", len(starts))
+	}
+}
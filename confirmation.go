@@ -0,0 +1,234 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultConfirmationTTLMinutes = 30
+
+// confirmationTTL reads CONFIRMATION_TOKEN_TTL_MINUTES, falling back to
+// defaultConfirmationTTLMinutes if unset or invalid.
+func confirmationTTL() time.Duration {
+	if raw := os.Getenv("CONFIRMATION_TOKEN_TTL_MINUTES"); raw != "" {
+		if minutes, err := strconv.Atoi(raw); err == nil && minutes > 0 {
+			return time.Duration(minutes) * time.Minute
+		}
+	}
+	return defaultConfirmationTTLMinutes * time.Minute
+}
+
+// generateConfirmationToken returns a cryptographically random 32 byte
+// token, hex-encoded.
+func generateConfirmationToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generating confirmation token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// isUniqueViolation reports whether err is a Postgres unique_violation,
+// i.e. the appointments_date_time_key constraint rejected an insert because
+// another request already booked that date/time first.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// pendingSlotTaken reports whether date/time is already occupied by a
+// booked appointment or a still-valid pending one, so a second visitor
+// can't be sent a confirmation link for a slot someone else is already
+// holding.
+func pendingSlotTaken(db *sql.DB, date, timeStr string) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM appointments WHERE date = $1 AND time = $2`, date, timeStr).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("checking booked appointments: %w", err)
+	}
+	if count > 0 {
+		return true, nil
+	}
+
+	cutoff := time.Now().Add(-confirmationTTL())
+	err = db.QueryRow(`
+		SELECT COUNT(*) FROM pending_appointments
+		WHERE date = $1 AND time = $2 AND confirmation_sent_at > $3`,
+		date, timeStr, cutoff).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("checking pending appointments: %w", err)
+	}
+	return count > 0, nil
+}
+
+// handleConfirmAppointment handles GET /api/appointments/confirm?token=...
+// It looks up the pending appointment by token, rejects stale or unknown
+// tokens, and otherwise atomically promotes the pending row into a real
+// appointment, re-checking slot availability inside the transaction.
+func handleConfirmAppointment(w http.ResponseWriter, r *http.Request, db *sql.DB, mailer EmailSender) {
+	logger := zerolog.Ctx(r.Context())
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		writeConfirmationPage(w, http.StatusBadRequest, "Fehlender Bestätigungslink", "Dieser Bestätigungslink ist ungültig.")
+		return
+	}
+
+	var pendingID int64
+	var appointment Appointment
+	var message sql.NullString
+	var sentAt time.Time
+	err := db.QueryRow(`
+		SELECT id, name, email, date, time, message, confirmation_sent_at
+		FROM pending_appointments WHERE confirmation_token = $1`, token).
+		Scan(&pendingID, &appointment.Name, &appointment.Email, &appointment.Date, &appointment.Time, &message, &sentAt)
+	if err == sql.ErrNoRows {
+		writeConfirmationPage(w, http.StatusNotFound, "Unbekannter Bestätigungslink", "Dieser Bestätigungslink wurde bereits verwendet oder ist ungültig.")
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("Database error looking up pending appointment")
+		writeConfirmationPage(w, http.StatusInternalServerError, "Fehler", "Es ist ein Fehler aufgetreten. Bitte versuchen Sie es später erneut.")
+		return
+	}
+	if message.Valid {
+		appointment.Message = &message.String
+	}
+
+	if time.Since(sentAt) > confirmationTTL() {
+		if _, err := db.Exec(`DELETE FROM pending_appointments WHERE id = $1`, pendingID); err != nil {
+			logger.Error().Err(err).Int64("id", pendingID).Msg("Failed to delete expired pending appointment")
+		}
+		writeConfirmationPage(w, http.StatusGone, "Link abgelaufen", "Dieser Bestätigungslink ist abgelaufen. Bitte buchen Sie Ihren Termin erneut.")
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to start confirmation transaction")
+		writeConfirmationPage(w, http.StatusInternalServerError, "Fehler", "Es ist ein Fehler aufgetreten. Bitte versuchen Sie es später erneut.")
+		return
+	}
+	defer tx.Rollback()
+
+	// A COUNT check here is only a fast path to skip the insert attempt for
+	// the common case; it can't itself prevent two concurrent confirmations
+	// from both reading zero rows. The appointments_date_time_key UNIQUE
+	// constraint is what actually closes the race, enforced below.
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM appointments WHERE date = $1 AND time = $2`,
+		appointment.Date, appointment.Time).Scan(&count); err != nil {
+		logger.Error().Err(err).Msg("Database error re-checking slot availability")
+		writeConfirmationPage(w, http.StatusInternalServerError, "Fehler", "Es ist ein Fehler aufgetreten. Bitte versuchen Sie es später erneut.")
+		return
+	}
+	if count > 0 {
+		tx.Exec(`DELETE FROM pending_appointments WHERE id = $1`, pendingID)
+		tx.Commit()
+		writeConfirmationPage(w, http.StatusConflict, "Termin nicht mehr verfügbar", "Dieser Termin wurde inzwischen anderweitig gebucht. Bitte wählen Sie einen anderen Termin.")
+		return
+	}
+
+	var id int64
+	err = tx.QueryRow(`
+		INSERT INTO appointments (name, email, date, time, message)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`,
+		appointment.Name, appointment.Email, appointment.Date, appointment.Time, appointment.Message,
+	).Scan(&id)
+	if isUniqueViolation(err) {
+		// The INSERT failure already put tx into Postgres's aborted state,
+		// so any further statement on tx (including COMMIT, which Postgres
+		// silently downgrades to a rollback) would be a no-op. Roll back
+		// explicitly and run the pending-row cleanup against db instead.
+		if err := tx.Rollback(); err != nil {
+			logger.Error().Err(err).Msg("Failed to roll back aborted confirmation transaction")
+		}
+		if _, err := db.Exec(`DELETE FROM pending_appointments WHERE id = $1`, pendingID); err != nil {
+			logger.Error().Err(err).Int64("id", pendingID).Msg("Failed to delete pending appointment after losing confirmation race")
+		}
+		writeConfirmationPage(w, http.StatusConflict, "Termin nicht mehr verfügbar", "Dieser Termin wurde inzwischen anderweitig gebucht. Bitte wählen Sie einen anderen Termin.")
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("Error creating appointment from pending confirmation")
+		writeConfirmationPage(w, http.StatusInternalServerError, "Fehler", "Es ist ein Fehler aufgetreten. Bitte versuchen Sie es später erneut.")
+		return
+	}
+
+	if _, err := tx.Exec(`DELETE FROM pending_appointments WHERE id = $1`, pendingID); err != nil {
+		logger.Error().Err(err).Int64("id", pendingID).Msg("Failed to delete pending appointment after confirmation")
+		writeConfirmationPage(w, http.StatusInternalServerError, "Fehler", "Es ist ein Fehler aufgetreten. Bitte versuchen Sie es später erneut.")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error().Err(err).Msg("Failed to commit confirmation transaction")
+		writeConfirmationPage(w, http.StatusInternalServerError, "Fehler", "Es ist ein Fehler aufgetreten. Bitte versuchen Sie es später erneut.")
+		return
+	}
+
+	appointment.ID = id
+	logger.Info().Int64("id", id).Str("email", appointment.Email).Msg("Appointment confirmed successfully")
+
+	icsData, err := buildAppointmentICS(r.Context(), appointment, "REQUEST")
+	if err != nil {
+		logger.Error().Err(err).Int64("id", id).Msg("Failed to build iCalendar invite for confirmed appointment")
+	} else if err := pushAppointmentToCaldav(r.Context(), id, icsData); err != nil {
+		logger.Error().Err(err).Int64("id", id).Msg("Failed to push confirmed appointment to CalDAV calendar")
+	}
+
+	if err := SendAppointmentInvite(r.Context(), mailer, appointment, "REQUEST"); err != nil {
+		logger.Error().Err(err).Int64("id", id).Msg("Failed to send appointment invite email")
+	}
+
+	writeConfirmationPage(w, http.StatusOK, "Termin bestätigt", fmt.Sprintf("Ihr Termin am %s um %s Uhr wurde bestätigt. Sie erhalten in Kürze eine Bestätigungs-E-Mail.", appointment.Date, appointment.Time))
+}
+
+// writeConfirmationPage renders a minimal, friendly HTML page for the
+// confirmation link flow, which is opened directly in the customer's
+// browser rather than consumed as JSON.
+func writeConfirmationPage(w http.ResponseWriter, status int, title, body string) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html lang="de">
+<head><meta charset="utf-8"><title>%s</title></head>
+<body style="font-family: sans-serif; max-width: 32rem; margin: 4rem auto; text-align: center;">
+<h1>%s</h1>
+<p>%s</p>
+</body>
+</html>`, title, title, body)
+}
+
+// startPendingAppointmentsCleanup launches a goroutine that periodically
+// deletes expired pending_appointments rows so abandoned confirmation
+// flows don't keep squatting slots forever.
+func startPendingAppointmentsCleanup(db *sql.DB) {
+	go func() {
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			cutoff := time.Now().Add(-confirmationTTL())
+			result, err := db.Exec(`DELETE FROM pending_appointments WHERE confirmation_sent_at <= $1`, cutoff)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to clean up expired pending appointments")
+				continue
+			}
+			if rows, err := result.RowsAffected(); err == nil && rows > 0 {
+				log.Info().Int64("rows", rows).Msg("Cleaned up expired pending appointments")
+			}
+		}
+	}()
+}
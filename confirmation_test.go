@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestConfirmationTTLDefault(t *testing.T) {
+	t.Setenv("CONFIRMATION_TOKEN_TTL_MINUTES", "")
+	if got := confirmationTTL(); got != defaultConfirmationTTLMinutes*time.Minute {
+		t.Errorf("confirmationTTL() = %v, want %v", got, defaultConfirmationTTLMinutes*time.Minute)
+	}
+}
+
+func TestConfirmationTTLFromEnv(t *testing.T) {
+	t.Setenv("CONFIRMATION_TOKEN_TTL_MINUTES", "15")
+	if got := confirmationTTL(); got != 15*time.Minute {
+		t.Errorf("confirmationTTL() = %v, want 15m", got)
+	}
+}
+
+func TestGenerateConfirmationTokenIsUniqueAndHex(t *testing.T) {
+	a, err := generateConfirmationToken()
+	if err != nil {
+		t.Fatalf("generateConfirmationToken() error: %v", err)
+	}
+	b, err := generateConfirmationToken()
+	if err != nil {
+		t.Fatalf("generateConfirmationToken() error: %v", err)
+	}
+	if a == b {
+		t.Error("expected two generated tokens to differ")
+	}
+	if len(a) != 64 {
+		t.Errorf("expected a 32 byte hex token (64 chars), got %d chars", len(a))
+	}
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	uniqueErr := &pq.Error{Code: "23505"}
+	wrapped := fmt.Errorf("inserting appointment: %w", uniqueErr)
+	if !isUniqueViolation(wrapped) {
+		t.Error("expected a wrapped unique_violation to be detected")
+	}
+
+	otherErr := &pq.Error{Code: "23502"}
+	if isUniqueViolation(otherErr) {
+		t.Error("expected a non-unique_violation pq.Error not to be detected")
+	}
+
+	if isUniqueViolation(errors.New("some other error")) {
+		t.Error("expected a non-pq error not to be detected as a unique violation")
+	}
+}
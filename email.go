@@ -1,51 +1,102 @@
 package main
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"os"
 
-	"github.com/resend/resend-go/v2"
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
 )
 
-func SendMail(receipientEmail, fullName string) error {
-	log.Info().Str("recipient", receipientEmail).Msg("Preparing to send email")
-	resendApiKey := os.Getenv("RESEND_API_KEY")
-	if resendApiKey == "" {
-		log.Error().Msg("RESEND_API_KEY is empty")
-		return errors.New("RESEND_API_KEY is empty")
+// buildContactMessage renders the "thanks for getting in touch" email,
+// including the Blueprint PDF attachment, as a provider-agnostic Message
+// ready to hand to an EmailSender.
+func buildContactMessage(ctx context.Context, contact ContactRequest) (Message, error) {
+	blueprintBytes, err := os.ReadFile("./assets/Blueprint.pdf")
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Msg("Failed to read attachment file")
+		return Message{}, err
 	}
-	client := resend.NewClient(resendApiKey)
 
-	blueprintBytes, err := os.ReadFile("./assets/Blueprint.pdf")
+	htmlBody := fmt.Sprintf("Hallo %v, <br><br> viel Erfolg bei der Automatisierung deiner Kanzlei! <br><br> Beste Grüße <br><br> Furkan Asani <br><br>", contact.FullName)
+
+	return Message{
+		To:      contact.Email,
+		Subject: "Ihr Blueprint für Ihre Kanzlei",
+		Html:    htmlBody,
+		Attachments: []Attachment{
+			{Filename: "Blueprint.pdf", Content: blueprintBytes, ContentType: "application/pdf"},
+		},
+	}, nil
+}
+
+// SendAppointmentInvite emails the customer an iCalendar attachment for the
+// given appointment through mailer. method should be "REQUEST" for a
+// new/updated booking or "CANCEL" when the appointment has been cancelled.
+func SendAppointmentInvite(ctx context.Context, mailer EmailSender, appointment Appointment, method string) error {
+	logger := zerolog.Ctx(ctx)
+	logger.Info().Str("recipient", appointment.Email).Str("method", method).Msg("Preparing appointment invite email")
 
+	icsData, err := buildAppointmentICS(ctx, appointment, method)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to read attachment file")
+		logger.Error().Err(err).Msg("Failed to build iCalendar invite")
 		return err
 	}
 
-	attachment := resend.Attachment{Content: blueprintBytes, Filename: "Blueprint.pdf"}
-
-	htmlBody := fmt.Sprintf("Hallo %v, <br><br> viel Erfolg bei der Automatisierung deiner Kanzlei! <br><br> Beste Grüße <br><br> Furkan Asani <br><br>", fullName)
-	params := &resend.SendEmailRequest{
-		From:        "AF Automation<info@af-automation-systems.com>",
-		To:          []string{receipientEmail},
-		Html:        htmlBody,
-		Subject:     "Ihr Blueprint für Ihre Kanzlei",
-		Cc:          []string{},
-		Bcc:         []string{"info@af-automation-systems.com"},
-		ReplyTo:     "info@af-automation-systems.com",
-		Attachments: []*resend.Attachment{&attachment},
+	subject := fmt.Sprintf("Terminbestätigung: %s um %s Uhr", appointment.Date, appointment.Time)
+	htmlBody := fmt.Sprintf("Hallo %v, <br><br> Ihr Termin am %v um %v Uhr wurde bestätigt. <br><br> Beste Grüße <br><br> Furkan Asani <br><br>", appointment.Name, appointment.Date, appointment.Time)
+	if method == "CANCEL" {
+		subject = fmt.Sprintf("Terminabsage: %s um %s Uhr", appointment.Date, appointment.Time)
+		htmlBody = fmt.Sprintf("Hallo %v, <br><br> Ihr Termin am %v um %v Uhr wurde storniert. <br><br> Beste Grüße <br><br> Furkan Asani <br><br>", appointment.Name, appointment.Date, appointment.Time)
 	}
 
-	log.Info().Msg("Sending email via Resend")
-	sent, err := client.Emails.Send(params)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to send email")
+	msg := Message{
+		To:      appointment.Email,
+		Subject: subject,
+		Html:    htmlBody,
+		Attachments: []Attachment{
+			{
+				Filename:    "termin.ics",
+				Content:     []byte(icsData),
+				ContentType: fmt.Sprintf("text/calendar; charset=utf-8; method=%s", method),
+			},
+		},
+	}
+
+	logger.Info().Msg("Sending appointment invite")
+	if err := mailer.Send(ctx, msg); err != nil {
+		logger.Error().Err(err).Msg("Failed to send appointment invite")
+		return err
+	}
+
+	logger.Info().Str("recipient", appointment.Email).Msg("Appointment invite sent successfully")
+	return nil
+}
+
+// SendAppointmentConfirmationRequest emails the customer, through mailer,
+// the double opt-in link they must open to turn their pending appointment
+// into a real booking.
+func SendAppointmentConfirmationRequest(ctx context.Context, mailer EmailSender, appointment Appointment, confirmLink string) error {
+	logger := zerolog.Ctx(ctx)
+	logger.Info().Str("recipient", appointment.Email).Msg("Preparing appointment confirmation request email")
+
+	htmlBody := fmt.Sprintf(
+		"Hallo %v, <br><br> bitte bestätigen Sie Ihren Termin am %v um %v Uhr über den folgenden Link: <br><br> <a href=\"%v\">%v</a> <br><br> Der Link ist %d Minuten gültig. <br><br> Beste Grüße <br><br> Furkan Asani <br><br>",
+		appointment.Name, appointment.Date, appointment.Time, confirmLink, confirmLink, int(confirmationTTL().Minutes()),
+	)
+
+	msg := Message{
+		To:      appointment.Email,
+		Subject: "Bitte bestätigen Sie Ihren Termin",
+		Html:    htmlBody,
+	}
+
+	logger.Info().Msg("Sending appointment confirmation request")
+	if err := mailer.Send(ctx, msg); err != nil {
+		logger.Error().Err(err).Msg("Failed to send appointment confirmation request")
 		return err
 	}
 
-	log.Info().Str("message_id", sent.Id).Str("recipient", receipientEmail).Msg("Email sent successfully")
+	logger.Info().Str("recipient", appointment.Email).Msg("Appointment confirmation request sent successfully")
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mailgun/mailgun-go/v4"
+	"github.com/resend/resend-go/v2"
+	"github.com/rs/zerolog"
+)
+
+// Attachment is a provider-agnostic email attachment.
+type Attachment struct {
+	Filename    string
+	Content     []byte
+	ContentType string
+}
+
+// Message is a provider-agnostic email to be sent through an EmailSender.
+type Message struct {
+	To          string
+	Subject     string
+	Html        string
+	Attachments []Attachment
+}
+
+// EmailSender abstracts over concrete mail providers so handlers don't need
+// to know which one is active.
+type EmailSender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewEmailSenderFromEnv selects the active backend from MAIL_PROVIDER
+// ("resend", the default; "mailgun"; "smtp"; or "noop") and wraps it with
+// retryingSender so transient provider outages don't silently drop mail.
+func NewEmailSenderFromEnv() EmailSender {
+	var sender EmailSender
+
+	switch strings.ToLower(os.Getenv("MAIL_PROVIDER")) {
+	case "mailgun":
+		sender = newMailgunSenderFromEnv()
+	case "smtp":
+		sender = newSMTPSenderFromEnv()
+	case "noop":
+		sender = NoopSender{}
+	default:
+		sender = newResendSenderFromEnv()
+	}
+
+	return &retryingSender{
+		inner:       sender,
+		maxAttempts: 4,
+		baseDelay:   500 * time.Millisecond,
+	}
+}
+
+// retryingSender retries a failed Send with exponential backoff
+// (500ms -> 2s -> 8s) before giving up. A permanentError (e.g. missing
+// provider credentials) is never retried, since retrying it just delays
+// the inevitable failure.
+type retryingSender struct {
+	inner       EmailSender
+	maxAttempts int
+	baseDelay   time.Duration
+}
+
+// permanentError marks a Send failure that retrying cannot fix, such as a
+// missing configuration value.
+type permanentError struct {
+	err error
+}
+
+func permanent(err error) error { return &permanentError{err: err} }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+func (s *retryingSender) Send(ctx context.Context, msg Message) error {
+	var lastErr error
+	delay := s.baseDelay
+	for attempt := 1; attempt <= s.maxAttempts; attempt++ {
+		lastErr = s.inner.Send(ctx, msg)
+		if lastErr == nil {
+			return nil
+		}
+
+		zerolog.Ctx(ctx).Warn().Err(lastErr).Int("attempt", attempt).Str("recipient", msg.To).Msg("Email send attempt failed")
+
+		var permErr *permanentError
+		if errors.As(lastErr, &permErr) {
+			return fmt.Errorf("sending email: %w", lastErr)
+		}
+		if attempt == s.maxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 4
+	}
+	return fmt.Errorf("sending email after %d attempts: %w", s.maxAttempts, lastErr)
+}
+
+// ResendSender sends mail through the Resend API.
+type ResendSender struct {
+	apiKey string
+}
+
+func newResendSenderFromEnv() ResendSender {
+	return ResendSender{apiKey: os.Getenv("RESEND_API_KEY")}
+}
+
+func (s ResendSender) Send(ctx context.Context, msg Message) error {
+	if s.apiKey == "" {
+		return permanent(errors.New("RESEND_API_KEY is empty"))
+	}
+	client := resend.NewClient(s.apiKey)
+
+	var attachments []*resend.Attachment
+	for _, a := range msg.Attachments {
+		attachments = append(attachments, &resend.Attachment{
+			Content:     a.Content,
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+		})
+	}
+
+	params := &resend.SendEmailRequest{
+		From:        "AF Automation<info@af-automation-systems.com>",
+		To:          []string{msg.To},
+		Html:        msg.Html,
+		Subject:     msg.Subject,
+		Cc:          []string{},
+		Bcc:         []string{"info@af-automation-systems.com"},
+		ReplyTo:     "info@af-automation-systems.com",
+		Attachments: attachments,
+	}
+
+	sent, err := client.Emails.Send(params)
+	if err != nil {
+		return fmt.Errorf("sending via Resend: %w", err)
+	}
+
+	zerolog.Ctx(ctx).Info().Str("message_id", sent.Id).Str("recipient", msg.To).Msg("Email sent via Resend")
+	return nil
+}
+
+// MailgunSender sends mail through the Mailgun API.
+type MailgunSender struct {
+	domain string
+	apiKey string
+}
+
+func newMailgunSenderFromEnv() MailgunSender {
+	return MailgunSender{
+		domain: os.Getenv("MAILGUN_DOMAIN"),
+		apiKey: os.Getenv("MAILGUN_API_KEY"),
+	}
+}
+
+func (s MailgunSender) Send(ctx context.Context, msg Message) error {
+	if s.domain == "" || s.apiKey == "" {
+		return permanent(errors.New("MAILGUN_DOMAIN and MAILGUN_API_KEY must be set"))
+	}
+	mg := mailgun.NewMailgun(s.domain, s.apiKey)
+
+	from := "AF Automation <info@af-automation-systems.com>"
+	m := mg.NewMessage(from, msg.Subject, "", msg.To)
+	m.SetHtml(msg.Html)
+	m.AddBCC("info@af-automation-systems.com")
+	for _, a := range msg.Attachments {
+		m.AddBufferAttachment(a.Filename, a.Content)
+	}
+
+	_, _, err := mg.Send(ctx, m)
+	if err != nil {
+		return fmt.Errorf("sending via Mailgun: %w", err)
+	}
+
+	zerolog.Ctx(ctx).Info().Str("recipient", msg.To).Msg("Email sent via Mailgun")
+	return nil
+}
+
+// SMTPSender sends mail through a generic SMTP server, with optional
+// STARTTLS.
+type SMTPSender struct {
+	host     string
+	port     string
+	user     string
+	password string
+	starttls bool
+}
+
+func newSMTPSenderFromEnv() SMTPSender {
+	starttls, _ := strconv.ParseBool(os.Getenv("SMTP_STARTTLS"))
+	return SMTPSender{
+		host:     os.Getenv("SMTP_HOST"),
+		port:     os.Getenv("SMTP_PORT"),
+		user:     os.Getenv("SMTP_USER"),
+		password: os.Getenv("SMTP_PASSWORD"),
+		starttls: starttls,
+	}
+}
+
+func (s SMTPSender) Send(ctx context.Context, msg Message) error {
+	if s.host == "" || s.port == "" {
+		return permanent(errors.New("SMTP_HOST and SMTP_PORT must be set"))
+	}
+
+	addr := fmt.Sprintf("%s:%s", s.host, s.port)
+	body := buildSMTPBody(msg)
+
+	var auth smtp.Auth
+	if s.user != "" {
+		auth = smtp.PlainAuth("", s.user, s.password, s.host)
+	}
+
+	if !s.starttls {
+		if err := smtp.SendMail(addr, auth, "info@af-automation-systems.com", []string{msg.To}, body); err != nil {
+			return fmt.Errorf("sending via SMTP: %w", err)
+		}
+		zerolog.Ctx(ctx).Info().Str("recipient", msg.To).Msg("Email sent via SMTP")
+		return nil
+	}
+
+	conn, err := smtp.Dial(addr)
+	if err != nil {
+		return fmt.Errorf("dialing SMTP server: %w", err)
+	}
+	defer conn.Close()
+
+	if err := conn.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+		return fmt.Errorf("starting SMTP TLS: %w", err)
+	}
+	if auth != nil {
+		if err := conn.Auth(auth); err != nil {
+			return fmt.Errorf("authenticating SMTP: %w", err)
+		}
+	}
+	if err := conn.Mail("info@af-automation-systems.com"); err != nil {
+		return fmt.Errorf("setting SMTP sender: %w", err)
+	}
+	if err := conn.Rcpt(msg.To); err != nil {
+		return fmt.Errorf("setting SMTP recipient: %w", err)
+	}
+	w, err := conn.Data()
+	if err != nil {
+		return fmt.Errorf("opening SMTP data stream: %w", err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return fmt.Errorf("writing SMTP body: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("closing SMTP data stream: %w", err)
+	}
+
+	zerolog.Ctx(ctx).Info().Str("recipient", msg.To).Msg("Email sent via SMTP with STARTTLS")
+	return nil
+}
+
+func buildSMTPBody(msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Html)
+	return []byte(b.String())
+}
+
+// NoopSender discards every message. Useful as the active backend in
+// environments where email delivery should be disabled entirely.
+type NoopSender struct{}
+
+func (NoopSender) Send(ctx context.Context, msg Message) error {
+	zerolog.Ctx(ctx).Debug().Str("recipient", msg.To).Msg("Noop email sender: discarding message")
+	return nil
+}
+
+// Recorder is an in-memory EmailSender that tests can assert against
+// instead of hitting a real provider.
+type Recorder struct {
+	mu       sync.Mutex
+	Messages []Message
+}
+
+func (r *Recorder) Send(ctx context.Context, msg Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Messages = append(r.Messages, msg)
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// countingSender fails the first failCount calls, then delegates to inner.
+type countingSender struct {
+	failCount int
+	err       error
+	calls     int
+	inner     EmailSender
+}
+
+func (s *countingSender) Send(ctx context.Context, msg Message) error {
+	s.calls++
+	if s.calls <= s.failCount {
+		return s.err
+	}
+	return s.inner.Send(ctx, msg)
+}
+
+func TestRetryingSenderSucceedsAfterTransientFailures(t *testing.T) {
+	recorder := &Recorder{}
+	sender := &countingSender{failCount: 2, err: errors.New("transient provider error"), inner: recorder}
+	retrying := &retryingSender{inner: sender, maxAttempts: 4, baseDelay: time.Millisecond}
+
+	if err := retrying.Send(context.Background(), Message{To: "a@example.com"}); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	if sender.calls != 3 {
+		t.Errorf("expected 3 attempts (2 failures + 1 success), got %d", sender.calls)
+	}
+	if len(recorder.Messages) != 1 {
+		t.Errorf("expected the message to be recorded exactly once, got %d", len(recorder.Messages))
+	}
+}
+
+func TestRetryingSenderGivesUpAfterMaxAttempts(t *testing.T) {
+	sender := &countingSender{failCount: 10, err: errors.New("provider is down")}
+	retrying := &retryingSender{inner: sender, maxAttempts: 4, baseDelay: time.Millisecond}
+
+	if err := retrying.Send(context.Background(), Message{To: "a@example.com"}); err == nil {
+		t.Fatal("expected Send to return an error once retries are exhausted")
+	}
+	if sender.calls != 4 {
+		t.Errorf("expected exactly maxAttempts (4) attempts, got %d", sender.calls)
+	}
+}
+
+func TestRetryingSenderDoesNotRetryPermanentErrors(t *testing.T) {
+	sender := &countingSender{failCount: 10, err: permanent(errors.New("RESEND_API_KEY is empty"))}
+	retrying := &retryingSender{inner: sender, maxAttempts: 4, baseDelay: time.Millisecond}
+
+	if err := retrying.Send(context.Background(), Message{To: "a@example.com"}); err == nil {
+		t.Fatal("expected Send to return an error for a permanent failure")
+	}
+	if sender.calls != 1 {
+		t.Errorf("expected a permanent error to be attempted exactly once, got %d", sender.calls)
+	}
+}
+
+func TestRecorderCapturesMessages(t *testing.T) {
+	recorder := &Recorder{}
+	msg := Message{To: "a@example.com", Subject: "hi"}
+	if err := recorder.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Recorder.Send returned error: %v", err)
+	}
+	if len(recorder.Messages) != 1 || recorder.Messages[0].Subject != "hi" {
+		t.Errorf("expected Recorder to capture the sent message, got %+v", recorder.Messages)
+	}
+}
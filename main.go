@@ -1,13 +1,18 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
 	"regexp"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	_ "github.com/lib/pq" // PostgreSQL driver
@@ -15,22 +20,24 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-// Constants
-var (
-	AVAILABLE_DAYS = []int{2, 4, 5} // Tuesday (2), Thursday (4), Friday (5)
-	BLOCKED_TIME   = struct {
-		Start string
-		End   string
-	}{
-		Start: "14:00",
-		End:   "14:30",
-	}
-)
+// App holds the shared dependencies injected into every handler: a single
+// connection pool (rather than opening one per request) and the active
+// email backend.
+type App struct {
+	db     *sql.DB
+	mailer EmailSender
+}
+
+// NewApp wires up an App from already-initialized dependencies.
+func NewApp(db *sql.DB, mailer EmailSender) *App {
+	return &App{db: db, mailer: mailer}
+}
 
 // Structs for request/response handling
+// TimeSlot is always a free, bookable slot: generateAvailableSlotsForDate
+// already excludes booked and blacked-out times before this struct is built.
 type TimeSlot struct {
-	Time     string `json:"time"`
-	IsBooked bool   `json:"isBooked"`
+	Time string `json:"time"`
 }
 
 type Appointment struct {
@@ -55,88 +62,55 @@ type ContactRequest struct {
 	Email    string `json:"email"`
 }
 
-// generateTimeSlots generates available time slots from 9:00 to 17:00
-func generateTimeSlots() []string {
-	var slots []string
-	for hour := 9; hour < 17; hour++ {
-		for minute := 0; minute < 60; minute += 30 {
-			timeString := fmt.Sprintf("%02d:%02d", hour, minute)
-
-			// Skip blocked time (14:00-14:30)
-			if timeString >= BLOCKED_TIME.Start && timeString < BLOCKED_TIME.End {
-				continue
-			}
-
-			slots = append(slots, timeString)
-		}
-	}
-	return slots
-}
+func (a *App) handleAppointments(w http.ResponseWriter, r *http.Request) {
+	logger := zerolog.Ctx(r.Context())
+	logger.Info().Str("method", r.Method).Str("path", r.URL.Path).Msg("Received request for appointments")
 
-// contains checks if a slice contains a value
-func contains(slice []int, item int) bool {
-	for _, v := range slice {
-		if v == item {
-			return true
+	if r.URL.Path == "/api/appointments/confirm" {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Content-Type", "application/json")
+			handleError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+			return
 		}
-	}
-	return false
-}
-
-func handleAppointments(w http.ResponseWriter, r *http.Request) {
-	log.Info().Str("method", r.Method).Str("path", r.URL.Path).Msg("Received request for appointments")
-	w.Header().Set("Content-Type", "application/json")
-
-	// Get database connection string from environment variable
-	dbURL := os.Getenv("DATABASE_URL")
-	if dbURL == "" {
-		handleError(w, "DATABASE_URL environment variable not set", http.StatusInternalServerError)
+		handleConfirmAppointment(w, r, a.db, a.mailer)
 		return
 	}
-
-	// Initialize database connection
-	db, err := sql.Open("postgres", dbURL)
-	if err != nil {
-		handleError(w, "Database connection error", http.StatusInternalServerError)
-		return
-	}
-	defer db.Close()
+	w.Header().Set("Content-Type", "application/json")
 
 	switch r.Method {
 	case http.MethodGet:
-		handleGetAppointments(w, r, db)
+		handleGetAppointments(w, r, a.db)
 	case http.MethodPost:
-		handlePostAppointment(w, r, db)
+		handlePostAppointment(w, r, a.db, a.mailer)
+	case http.MethodDelete:
+		requireBearerToken(func(w http.ResponseWriter, r *http.Request) {
+			handleDeleteAppointment(w, r, a.db, a.mailer)
+		})(w, r)
 	default:
-		handleError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		handleError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
 func handleGetAppointments(w http.ResponseWriter, r *http.Request, db *sql.DB) {
+	logger := zerolog.Ctx(r.Context())
 	date := r.URL.Query().Get("date")
-	log.Info().Str("date", date).Msg("Handling GET appointments request")
+	logger.Info().Str("date", date).Msg("Handling GET appointments request")
 	if date == "" {
-		handleError(w, "Date is required", http.StatusBadRequest)
+		handleError(w, r, "Date is required", http.StatusBadRequest)
 		return
 	}
 
 	// Parse and validate date
 	parsedDate, err := time.Parse("2006-01-02", date)
 	if err != nil {
-		handleError(w, "Invalid date format", http.StatusBadRequest)
-		return
-	}
-
-	dayOfWeek := int(parsedDate.Weekday())
-	if !contains(AVAILABLE_DAYS, dayOfWeek) {
-		json.NewEncoder(w).Encode(AppointmentResponse{Slots: []TimeSlot{}})
+		handleError(w, r, "Invalid date format", http.StatusBadRequest)
 		return
 	}
 
 	// Get booked appointments
 	rows, err := db.Query("SELECT time FROM appointments WHERE date = $1", date)
 	if err != nil {
-		handleError(w, "Database error", http.StatusInternalServerError)
+		handleError(w, r, "Database error", http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
@@ -145,16 +119,16 @@ func handleGetAppointments(w http.ResponseWriter, r *http.Request, db *sql.DB) {
 	for rows.Next() {
 		var t string
 		if err := rows.Scan(&t); err != nil {
-			handleError(w, "Database error", http.StatusInternalServerError)
+			handleError(w, r, "Database error", http.StatusInternalServerError)
 			return
 		}
 		// Normalize time format (remove seconds if present)
 		if t == "" {
-			handleError(w, "Time of booked appointment was empty", http.StatusInternalServerError)
+			handleError(w, r, "Time of booked appointment was empty", http.StatusInternalServerError)
 		}
 		splittedString := strings.Split(t, "T")
 		if len(splittedString) != 2 {
-			handleError(w, "Time string was not in correct format with a T as a separator. Please clean the data!", http.StatusInternalServerError)
+			handleError(w, r, "Time string was not in correct format with a T as a separator. Please clean the data!", http.StatusInternalServerError)
 		}
 		t = splittedString[1]
 
@@ -164,170 +138,251 @@ func handleGetAppointments(w http.ResponseWriter, r *http.Request, db *sql.DB) {
 		bookedTimes[t] = true
 	}
 
-	// Generate all slots with booking status
-	allSlots := generateTimeSlots()
+	// Fold in VEVENTs found directly on the owner's CalDAV calendar, so
+	// slots blocked outside of this API (e.g. personal events) are also
+	// shown as booked.
+	caldavBooked, err := fetchCaldavBookedTimes(r.Context(), date)
+	if err != nil {
+		logger.Error().Err(err).Str("date", date).Msg("Failed to query CalDAV calendar for booked times")
+	} else {
+		for t := range caldavBooked {
+			bookedTimes[t] = true
+		}
+	}
+
+	// Compute bookable slots by intersecting availability rules with the
+	// day, then subtracting blackout intervals and existing bookings.
+	allSlots, err := generateAvailableSlotsForDate(db, parsedDate, bookedTimes)
+	if err != nil {
+		logger.Error().Err(err).Str("date", date).Msg("Failed to compute availability")
+		handleError(w, r, "Database error", http.StatusInternalServerError)
+		return
+	}
+
 	slotsWithStatus := make([]TimeSlot, len(allSlots))
 	for i, slot := range allSlots {
-		slotsWithStatus[i] = TimeSlot{
-			Time:     slot,
-			IsBooked: bookedTimes[slot],
-		}
+		slotsWithStatus[i] = TimeSlot{Time: slot}
 	}
 
-	log.Info().Int("available_slots", len(slotsWithStatus)).Str("date", date).Msg("Successfully retrieved appointment slots")
+	logger.Info().Int("available_slots", len(slotsWithStatus)).Str("date", date).Msg("Successfully retrieved appointment slots")
 	json.NewEncoder(w).Encode(AppointmentResponse{Slots: slotsWithStatus})
 }
 
-func handlePostAppointment(w http.ResponseWriter, r *http.Request, db *sql.DB) {
+func handlePostAppointment(w http.ResponseWriter, r *http.Request, db *sql.DB, mailer EmailSender) {
+	logger := zerolog.Ctx(r.Context())
 	var appointment Appointment
-	log.Info().Msg("Handling POST appointment request")
+	logger.Info().Msg("Handling POST appointment request")
 	if err := json.NewDecoder(r.Body).Decode(&appointment); err != nil {
-		log.Error().Err(err).Msg("Failed to decode request body")
-		handleError(w, "Invalid request body", http.StatusBadRequest)
+		logger.Error().Err(err).Msg("Failed to decode request body")
+		handleError(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	// Validate required fields
 	if appointment.Name == "" || appointment.Email == "" ||
 		appointment.Date == "" || appointment.Time == "" {
-		handleError(w, "Name, email, date, and time are required", http.StatusBadRequest)
+		handleError(w, r, "Name, email, date, and time are required", http.StatusBadRequest)
 		return
 	}
 
 	// Email validation
 	emailRegex := regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
 	if !emailRegex.MatchString(appointment.Email) {
-		handleError(w, "Invalid email format", http.StatusBadRequest)
+		handleError(w, r, "Invalid email format", http.StatusBadRequest)
 		return
 	}
 
 	// Parse and validate date
 	parsedDate, err := time.Parse("2006-01-02", appointment.Date)
 	if err != nil {
-		handleError(w, "Invalid date format", http.StatusBadRequest)
+		handleError(w, r, "Invalid date format", http.StatusBadRequest)
 		return
 	}
 
-	dayOfWeek := int(parsedDate.Weekday())
-	if !contains(AVAILABLE_DAYS, dayOfWeek) {
-		handleError(w, "This day is not available for appointments", http.StatusBadRequest)
+	available, err := isSlotAvailable(db, parsedDate, appointment.Time)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to check availability")
+		handleError(w, r, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !available {
+		handleError(w, r, "This day or time is not available for appointments", http.StatusBadRequest)
 		return
 	}
 
-	// Check if time slot is available
-	var count int
-	err = db.QueryRow("SELECT COUNT(*) FROM appointments WHERE date = $1 AND time = $2",
-		appointment.Date, appointment.Time).Scan(&count)
+	// Check if the slot is already booked or held by a still-valid pending
+	// confirmation, so two visitors can't squat the same slot at once.
+	taken, err := pendingSlotTaken(db, appointment.Date, appointment.Time)
 	if err != nil {
-		handleError(w, "Database error", http.StatusInternalServerError)
+		logger.Error().Err(err).Msg("Failed to check slot availability")
+		handleError(w, r, "Database error", http.StatusInternalServerError)
 		return
 	}
-	if count > 0 {
-		handleError(w, "This time slot is already booked", http.StatusBadRequest)
+	if taken {
+		handleError(w, r, "This time slot is already booked", http.StatusBadRequest)
 		return
 	}
 
-	// Create the appointment
-	var id int64
+	token, err := generateConfirmationToken()
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to generate confirmation token")
+		handleError(w, r, "Error creating appointment", http.StatusInternalServerError)
+		return
+	}
+
+	appointment.Name = strings.TrimSpace(appointment.Name)
+	appointment.Email = strings.ToLower(strings.TrimSpace(appointment.Email))
+
+	var pendingID int64
 	err = db.QueryRow(`
-		INSERT INTO appointments (name, email, date, time, message)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO pending_appointments (name, email, date, time, message, confirmation_token, confirmation_sent_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
 		RETURNING id`,
-		strings.TrimSpace(appointment.Name),
-		strings.ToLower(strings.TrimSpace(appointment.Email)),
+		appointment.Name,
+		appointment.Email,
 		appointment.Date,
 		appointment.Time,
 		appointment.Message,
-	).Scan(&id)
+		token,
+	).Scan(&pendingID)
 
 	if err != nil {
-		log.Error().Err(err).Msg("Error creating appointment")
-		handleError(w, "Error creating appointment", http.StatusInternalServerError)
+		logger.Error().Err(err).Msg("Error creating pending appointment")
+		handleError(w, r, "Error creating appointment", http.StatusInternalServerError)
 		return
 	}
 
-	appointment.ID = id
-	log.Info().
+	logger.Info().
+		Int64("pending_id", pendingID).
 		Str("name", appointment.Name).
 		Str("email", appointment.Email).
 		Str("date", appointment.Date).
 		Str("time", appointment.Time).
-		Msg("Appointment booked successfully")
+		Msg("Pending appointment created, awaiting confirmation")
+
+	confirmLink := fmt.Sprintf("https://%s/api/appointments/confirm?token=%s", r.Host, token)
+	if err := SendAppointmentConfirmationRequest(r.Context(), mailer, appointment, confirmLink); err != nil {
+		logger.Error().Err(err).Int64("pending_id", pendingID).Msg("Failed to send appointment confirmation email")
+		handleError(w, r, "Error sending confirmation email", http.StatusInternalServerError)
+		return
+	}
+
 	json.NewEncoder(w).Encode(AppointmentResponse{
 		Success:     true,
-		Message:     "Appointment booked successfully",
+		Message:     "Please check your email to confirm your appointment.",
 		Appointment: appointment,
 	})
 }
 
-func handleContacts(w http.ResponseWriter, r *http.Request) {
-	log.Info().Str("method", r.Method).Str("path", r.URL.Path).Msg("Received request for contacts")
-	w.Header().Set("Content-Type", "application/json")
-
-	// Get database connection string from environment variable
-	dbURL := os.Getenv("DATABASE_URL")
-	if dbURL == "" {
-		handleError(w, "DATABASE_URL environment variable not set", http.StatusInternalServerError)
+// handleDeleteAppointment cancels a booking: it removes the row from
+// appointments, deletes the corresponding CalDAV object, and emails the
+// customer an iCalendar METHOD:CANCEL update. Appointment ids are sequential,
+// so the caller must be wrapped in requireBearerToken before reaching here.
+func handleDeleteAppointment(w http.ResponseWriter, r *http.Request, db *sql.DB, mailer EmailSender) {
+	logger := zerolog.Ctx(r.Context())
+	idParam := strings.TrimPrefix(r.URL.Path, "/api/appointments/")
+	idParam = strings.TrimSuffix(idParam, "/")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil || idParam == "" {
+		handleError(w, r, "A valid appointment id is required in the path", http.StatusBadRequest)
 		return
 	}
 
-	// Initialize database connection
-	db, err := sql.Open("postgres", dbURL)
+	var appointment Appointment
+	var message sql.NullString
+	err = db.QueryRow(`SELECT id, name, email, date, time, message FROM appointments WHERE id = $1`, id).
+		Scan(&appointment.ID, &appointment.Name, &appointment.Email, &appointment.Date, &appointment.Time, &message)
+	if err == sql.ErrNoRows {
+		handleError(w, r, "Appointment not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		handleError(w, "Database connection error", http.StatusInternalServerError)
+		logger.Error().Err(err).Int64("id", id).Msg("Database error looking up appointment")
+		handleError(w, r, "Database error", http.StatusInternalServerError)
 		return
 	}
-	defer db.Close()
+	if message.Valid {
+		appointment.Message = &message.String
+	}
+
+	if _, err := db.Exec(`DELETE FROM appointments WHERE id = $1`, id); err != nil {
+		logger.Error().Err(err).Int64("id", id).Msg("Error deleting appointment")
+		handleError(w, r, "Error cancelling appointment", http.StatusInternalServerError)
+		return
+	}
+
+	if err := deleteAppointmentFromCaldav(r.Context(), id); err != nil {
+		logger.Error().Err(err).Int64("id", id).Msg("Failed to delete appointment from CalDAV calendar")
+	}
+
+	if err := SendAppointmentInvite(r.Context(), mailer, appointment, "CANCEL"); err != nil {
+		logger.Error().Err(err).Int64("id", id).Msg("Failed to send appointment cancellation email")
+	}
+
+	logger.Info().Int64("id", id).Msg("Appointment cancelled successfully")
+	json.NewEncoder(w).Encode(AppointmentResponse{
+		Success: true,
+		Message: "Appointment cancelled successfully",
+	})
+}
+
+func (a *App) handleContacts(w http.ResponseWriter, r *http.Request) {
+	logger := zerolog.Ctx(r.Context())
+	logger.Info().Str("method", r.Method).Str("path", r.URL.Path).Msg("Received request for contacts")
+	w.Header().Set("Content-Type", "application/json")
+
+	db := a.db
 
 	if r.Method != http.MethodPost {
-		handleError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		handleError(w, r, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	var contact ContactRequest
 	if err := json.NewDecoder(r.Body).Decode(&contact); err != nil {
-		log.Error().Err(err).Msg("Failed to decode contact request body")
-		handleError(w, "Invalid request body", http.StatusBadRequest)
+		logger.Error().Err(err).Msg("Failed to decode contact request body")
+		handleError(w, r, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	if contact.FullName == "" || contact.Email == "" {
-		handleError(w, "Full name and email are required", http.StatusBadRequest)
+		handleError(w, r, "Full name and email are required", http.StatusBadRequest)
 		return
 	}
 
 	// Email validation
 	emailRegex := regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
 	if !emailRegex.MatchString(contact.Email) {
-		handleError(w, "Invalid email format", http.StatusBadRequest)
+		handleError(w, r, "Invalid email format", http.StatusBadRequest)
 		return
 	}
 
-	_, err = db.Exec(`INSERT INTO contacts (name, email) VALUES ($1, $2)`, contact.FullName, contact.Email)
-	if err != nil {
-		log.Error().Err(err).Str("email", contact.Email).Msg("Error saving contact")
-		handleError(w, "Error saving contact", http.StatusInternalServerError)
+	if _, err := db.Exec(`INSERT INTO contacts (name, email) VALUES ($1, $2)`, contact.FullName, contact.Email); err != nil {
+		logger.Error().Err(err).Str("email", contact.Email).Msg("Error saving contact")
+		handleError(w, r, "Error saving contact", http.StatusInternalServerError)
 		return
 	}
-	log.Info().Str("email", contact.Email).Msg("Contact saved successfully")
+	logger.Info().Str("email", contact.Email).Msg("Contact saved successfully")
 
 	// Send email with PDF attachment
-	if err := SendMail(contact.Email, contact.FullName); err != nil {
+	if msg, err := buildContactMessage(r.Context(), contact); err != nil {
+		logger.Error().Err(err).Str("email", contact.Email).Msg("Failed to build contact email")
+	} else if err := a.mailer.Send(r.Context(), msg); err != nil {
 		// Log the email error but don't fail the request,
 		// as the contact has already been saved.
-		log.Error().Err(err).Str("email", contact.Email).Msg("Failed to send contact email")
+		logger.Error().Err(err).Str("email", contact.Email).Msg("Failed to send contact email")
 	}
 
 	w.WriteHeader(http.StatusOK)
-	log.Info().Str("email", contact.Email).Msg("Contact request processed successfully")
+	logger.Info().Str("email", contact.Email).Msg("Contact request processed successfully")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
 		"message": "Contact received and email sent.",
 	})
 }
 
-func handleError(w http.ResponseWriter, message string, status int) {
-	log.Error().Int("status", status).Msg(message)
+func handleError(w http.ResponseWriter, r *http.Request, message string, status int) {
+	zerolog.Ctx(r.Context()).Error().Int("status", status).Msg(message)
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(AppointmentResponse{
 		Error: message,
@@ -361,28 +416,105 @@ func corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// openDB opens the shared connection pool against DATABASE_URL, tunes its
+// size/lifetime, and verifies connectivity with a Ping before the server
+// starts serving traffic.
+func openDB() (*sql.DB, error) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		return nil, errors.New("DATABASE_URL environment variable not set")
+	}
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("opening database connection: %w", err)
+	}
+
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(25)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("pinging database: %w", err)
+	}
+
+	return db, nil
+}
+
 func main() {
 	// Initialize logger
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 	// Pretty logging for development
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339})
 
-	// Wrap the original handler with the CORS middleware
-	appointmentsHandler := http.HandlerFunc(handleAppointments)
-	http.Handle("/api/appointments", corsMiddleware(appointmentsHandler))
+	db, err := openDB()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer db.Close()
+
+	if err := runMigrations(db); err != nil {
+		log.Fatal().Err(err).Msg("Failed to run database migrations")
+	}
+
+	app := NewApp(db, NewEmailSenderFromEnv())
+
+	mux := http.NewServeMux()
+	appointmentsHandler := http.HandlerFunc(app.handleAppointments)
+	mux.Handle("/api/appointments", corsMiddleware(appointmentsRateLimit(appointmentsHandler)))
+	mux.Handle("/api/appointments/", corsMiddleware(appointmentsRateLimit(appointmentsHandler)))
+
+	mux.Handle("/api/contacts", corsMiddleware(contactsRateLimiter.middleware(http.HandlerFunc(app.handleContacts))))
+
+	mux.Handle("/api/availability/rules", corsMiddleware(defaultRateLimiter.middleware(requireBearerToken(app.handleAvailabilityRules))))
+	mux.Handle("/api/availability/blackouts", corsMiddleware(defaultRateLimiter.middleware(requireBearerToken(app.handleBlackoutPeriods))))
 
-	contactsHandler := http.HandlerFunc(handleContacts)
-	http.Handle("/api/contacts", corsMiddleware(contactsHandler))
+	startPendingAppointmentsCleanup(app.db)
+	startRateLimiterCleanup()
 
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	log.Info().Str("port", port).Msg("Starting server")
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatal().Err(err).Msg("Server failed to start")
+	// Middleware runs outermost-first per request: each request gets an ID
+	// and a scoped logger before anything else sees it, then recovery guards
+	// the rest of the chain and logs any panic with that request's ID.
+	handler := requestIDMiddleware(recoveryMiddleware(mux))
+
+	server := &http.Server{
+		Addr:    ":" + port,
+		Handler: handler,
 	}
+
+	serverErrors := make(chan error, 1)
+	go func() {
+		log.Info().Str("port", port).Msg("Starting server")
+		serverErrors <- server.ListenAndServe()
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	select {
+	case err := <-serverErrors:
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatal().Err(err).Msg("Server failed to start")
+		}
+	case <-ctx.Done():
+		log.Info().Msg("Shutdown signal received, draining in-flight requests")
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Error().Err(err).Msg("Error during server shutdown")
+		}
+	}
+
+	log.Info().Msg("Server stopped")
 }
 
 
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/time/rate"
+)
+
+// requestIDMiddleware assigns each request a ULID, echoes it back as
+// X-Request-ID, and stores a zerolog sub-logger carrying that ID on the
+// request context so every log line for this request can be correlated.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := ulid.Make().String()
+		w.Header().Set("X-Request-ID", requestID)
+
+		requestLogger := log.With().Str("request_id", requestID).Logger()
+		ctx := requestLogger.WithContext(r.Context())
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// recoveryMiddleware catches panics from downstream handlers, logs the
+// stack trace, and returns a JSON 500 instead of crashing the server.
+func recoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				zerolog.Ctx(r.Context()).Error().
+					Interface("panic", rec).
+					Bytes("stack", debug.Stack()).
+					Msg("Recovered from panic")
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(AppointmentResponse{Error: "Internal server error"})
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// trustProxyHeaders reports whether TRUST_PROXY_HEADERS is set, i.e.
+// whether the server sits behind a reverse proxy we control that sets
+// X-Forwarded-For itself. Without it, the header is attacker-controlled
+// and honoring it would let a client pick its own rate-limit bucket.
+func trustProxyHeaders() bool {
+	trust, _ := strconv.ParseBool(os.Getenv("TRUST_PROXY_HEADERS"))
+	return trust
+}
+
+// clientIP extracts the caller's IP for rate-limiting purposes. It only
+// consults X-Forwarded-For when trustProxyHeaders is set; otherwise it
+// always uses RemoteAddr, since an unauthenticated caller can set XFF to
+// anything and get a fresh token bucket on every request.
+func clientIP(r *http.Request) string {
+	if trustProxyHeaders() {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			if first := strings.TrimSpace(strings.Split(forwarded, ",")[0]); first != "" {
+				return first
+			}
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// visitor pairs a client's token bucket with the last time it was used, so
+// stale entries can be evicted.
+type visitor struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// ipRateLimiter keeps one token-bucket limiter per client IP.
+type ipRateLimiter struct {
+	mu       sync.Mutex
+	visitors map[string]*visitor
+	rate     rate.Limit
+	burst    int
+}
+
+func newIPRateLimiter(r rate.Limit, burst int) *ipRateLimiter {
+	return &ipRateLimiter{
+		visitors: make(map[string]*visitor),
+		rate:     r,
+		burst:    burst,
+	}
+}
+
+func (rl *ipRateLimiter) limiterFor(key string) *rate.Limiter {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	v, ok := rl.visitors[key]
+	if !ok {
+		v = &visitor{limiter: rate.NewLimiter(rl.rate, rl.burst)}
+		rl.visitors[key] = v
+	}
+	v.lastSeen = time.Now()
+	return v.limiter
+}
+
+// evictStale removes visitors that haven't made a request in maxAge, so
+// the map doesn't grow without bound.
+func (rl *ipRateLimiter) evictStale(maxAge time.Duration) {
+	cutoff := time.Now().Add(-maxAge)
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	for key, v := range rl.visitors {
+		if v.lastSeen.Before(cutoff) {
+			delete(rl.visitors, key)
+		}
+	}
+}
+
+// middleware returns a handler that rejects requests from a client once
+// its token bucket is empty, responding 429 with a Retry-After header.
+func (rl *ipRateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limiter := rl.limiterFor(clientIP(r))
+		if !limiter.Allow() {
+			zerolog.Ctx(r.Context()).Warn().Str("client_ip", clientIP(r)).Str("path", r.URL.Path).Msg("Rate limit exceeded")
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(AppointmentResponse{Error: "Too many requests"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Default per-route rate limits. Contacts and appointment creation are
+// stricter since they're unauthenticated and trigger outbound email/CalDAV
+// calls; everything else gets a looser default.
+var (
+	contactsRateLimiter     = newIPRateLimiter(rate.Every(time.Minute/5), 5)
+	appointmentsPostLimiter = newIPRateLimiter(rate.Every(time.Minute/10), 10)
+	defaultRateLimiter      = newIPRateLimiter(rate.Every(time.Second), 20)
+)
+
+// appointmentsRateLimit applies a stricter bucket to POST (new bookings)
+// and the default bucket to every other method on the appointments routes.
+func appointmentsRateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			appointmentsPostLimiter.middleware(next).ServeHTTP(w, r)
+			return
+		}
+		defaultRateLimiter.middleware(next).ServeHTTP(w, r)
+	})
+}
+
+// visitorIdleTimeout is how long a client IP can go without a request
+// before its rate limiter entry is evicted.
+const visitorIdleTimeout = time.Hour
+
+// startRateLimiterCleanup launches a goroutine that periodically evicts
+// idle visitors from every rate limiter, so a flood of distinct (or
+// spoofed) client IPs can't grow the visitors maps unboundedly.
+func startRateLimiterCleanup() {
+	go func() {
+		ticker := time.NewTicker(10 * time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			for _, rl := range []*ipRateLimiter{contactsRateLimiter, appointmentsPostLimiter, defaultRateLimiter} {
+				rl.evictStale(visitorIdleTimeout)
+			}
+		}
+	}()
+}
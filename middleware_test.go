@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestClientIPIgnoresForwardedForByDefault(t *testing.T) {
+	t.Setenv("TRUST_PROXY_HEADERS", "")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.10:1234"
+	req.Header.Set("X-Forwarded-For", "198.51.100.99")
+
+	if got := clientIP(req); got != "203.0.113.10" {
+		t.Errorf("clientIP() = %q, want RemoteAddr host 203.0.113.10", got)
+	}
+}
+
+func TestClientIPHonorsForwardedForWhenTrusted(t *testing.T) {
+	t.Setenv("TRUST_PROXY_HEADERS", "true")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.10:1234"
+	req.Header.Set("X-Forwarded-For", "198.51.100.99, 203.0.113.10")
+
+	if got := clientIP(req); got != "198.51.100.99" {
+		t.Errorf("clientIP() = %q, want first X-Forwarded-For hop", got)
+	}
+}
+
+func TestIPRateLimiterEvictsStaleVisitors(t *testing.T) {
+	rl := newIPRateLimiter(rate.Every(time.Second), 5)
+	rl.limiterFor("1.2.3.4")
+	rl.limiterFor("5.6.7.8")
+
+	if len(rl.visitors) != 2 {
+		t.Fatalf("expected 2 visitors before eviction, got %d", len(rl.visitors))
+	}
+
+	rl.evictStale(-time.Second) // every visitor is "older" than now minus a negative duration
+	if len(rl.visitors) != 0 {
+		t.Errorf("expected evictStale to remove every visitor, got %d left", len(rl.visitors))
+	}
+}
+
+func TestIPRateLimiterKeepsRecentVisitors(t *testing.T) {
+	rl := newIPRateLimiter(rate.Every(time.Second), 5)
+	rl.limiterFor("1.2.3.4")
+
+	rl.evictStale(time.Hour)
+	if len(rl.visitors) != 1 {
+		t.Errorf("expected a recently-seen visitor to survive eviction, got %d left", len(rl.visitors))
+	}
+}